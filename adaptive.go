@@ -0,0 +1,162 @@
+package token
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AdaptiveOpts configures an Adaptive controller.
+type AdaptiveOpts struct {
+	// Increment is added to the current capacity for every Interval that
+	// saw at least one Success call not followed by a Failure or Throttled
+	// call. Defaults to 1.
+	Increment float64
+	// Backoff multiplies the current capacity on Throttled. Defaults to 0.5.
+	Backoff float64
+	// Interval coalesces successes: at most one additive increase is
+	// applied per Interval, so a burst of Success calls does not spam
+	// SetCapacity. Defaults to time.Second.
+	Interval time.Duration
+}
+
+// withDefaults returns a copy of opts with zero/invalid fields replaced by
+// their defaults.
+func (o AdaptiveOpts) withDefaults() AdaptiveOpts {
+	if o.Increment <= 0 {
+		o.Increment = 1
+	}
+	if o.Backoff <= 0 || o.Backoff >= 1 {
+		o.Backoff = 0.5
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	return o
+}
+
+// Adaptive wraps a Token and adjusts its capacity automatically based on
+// success/failure feedback from downstream calls, similar to TCP's AIMD
+// congestion control: capacity increases additively on sustained success
+// and drops multiplicatively the moment a call is throttled.
+type Adaptive struct {
+	tok  *Token
+	opts AdaptiveOpts
+
+	mu      sync.Mutex
+	current float64
+	// pending is true if at least one Success has been recorded since the
+	// last tick applied an increase and since the last Failure or Throttled.
+	pending bool
+	// applied is the integer capacity last pushed to tok via adjustCapacity.
+	// Adjustments are expressed as a delta off of it, rather than an
+	// absolute overwrite, so they compose with tokens currently checked
+	// out via Take instead of clobbering them.
+	applied int
+}
+
+// NewAdaptive creates an Adaptive controller for tok, starting at half of
+// tok's maximum capacity, and starts the background ticker that coalesces
+// additive increases. It runs until tok is closed.
+//
+// Callers should invoke Success, Failure or Throttled once per downstream
+// request, based on its outcome.
+func NewAdaptive(tok *Token, opts AdaptiveOpts) *Adaptive {
+	opts = opts.withDefaults()
+
+	a := &Adaptive{
+		tok:     tok,
+		opts:    opts,
+		current: math.Max(1, float64(tok.MaxCapacity())/2),
+	}
+	a.setCurrentLocked(a.current)
+
+	go a.run()
+
+	return a
+}
+
+// run applies at most one additive increase per Interval, as long as a
+// Success was recorded since the last tick, until tok is closed.
+func (a *Adaptive) run() {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.tok.ctx.Done():
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.pending {
+				a.pending = false
+				a.setCurrentLocked(a.current + a.opts.Increment)
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// Success reports that a downstream request succeeded. The resulting
+// additive increase is coalesced and applied on the next tick.
+func (a *Adaptive) Success() {
+	a.mu.Lock()
+	a.pending = true
+	a.mu.Unlock()
+}
+
+// Failure reports that a downstream request failed for a reason unrelated
+// to rate limiting (e.g. a 5xx response). It cancels any increase that was
+// pending for the next tick, but does not otherwise change capacity;
+// callers that want such responses to also back off should call Throttled.
+func (a *Adaptive) Failure() {
+	a.mu.Lock()
+	a.pending = false
+	a.mu.Unlock()
+}
+
+// Throttled reports that a downstream request was rejected for being too
+// fast (e.g. a 429 response). It immediately and multiplicatively decreases
+// the capacity by opts.Backoff, down to a floor of 1.
+func (a *Adaptive) Throttled() {
+	a.mu.Lock()
+	a.pending = false
+	a.setCurrentLocked(a.current * a.opts.Backoff)
+	a.mu.Unlock()
+}
+
+// Current returns the controller's current capacity estimate.
+func (a *Adaptive) Current() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(math.Round(a.current))
+}
+
+// setCurrentLocked clamps target to [1, maxCapacity] and stores it. Token's
+// SetCapacity overwrites the bucket's available count absolutely, with no
+// notion of tokens currently checked out via Take, so pushing the rounded
+// target directly would make the controller fight outstanding callers.
+// Instead this applies only the delta since the last push, via
+// adjustCapacity, which reads and writes the bucket's available count under
+// a single lock acquisition so a concurrent Take or Release isn't
+// clobbered. Callers must hold a.mu.
+func (a *Adaptive) setCurrentLocked(target float64) {
+	maxCap := float64(a.tok.MaxCapacity())
+
+	if target < 1 {
+		target = 1
+	}
+	if target > maxCap {
+		target = maxCap
+	}
+	a.current = target
+
+	rounded := int(math.Round(target))
+	delta := rounded - a.applied
+	if delta == 0 {
+		return
+	}
+
+	a.tok.adjustCapacity(delta)
+	a.applied = rounded
+}
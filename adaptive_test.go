@@ -0,0 +1,144 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewAdaptive(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{})
+	if got := a.Current(); got != 5 {
+		t.Errorf("Current() = %d, want 5 (maxCapacity/2)", got)
+	}
+	if got := tok.Available(); got != 5 {
+		t.Errorf("Available() after NewAdaptive = %d, want 5", got)
+	}
+}
+
+func TestAdaptive_Throttled(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Backoff: 0.5})
+
+	a.Throttled()
+	if got := a.Current(); got != 3 {
+		t.Errorf("Current() after one Throttled() = %d, want 3 (round(5*0.5))", got)
+	}
+	if got := tok.Available(); got != 3 {
+		t.Errorf("Available() after Throttled() = %d, want 3", got)
+	}
+}
+
+func TestAdaptive_ThrottledFloor(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Backoff: 0.1})
+
+	for i := 0; i < 10; i++ {
+		a.Throttled()
+	}
+
+	if got := a.Current(); got != 1 {
+		t.Errorf("Current() after repeated Throttled() = %d, want floor of 1", got)
+	}
+}
+
+func TestAdaptive_SuccessCoalesced(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Increment: 2, Interval: 30 * time.Millisecond})
+
+	// A burst of successes within one interval should only apply once.
+	for i := 0; i < 20; i++ {
+		a.Success()
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if got := a.Current(); got != 7 {
+		t.Errorf("Current() after a coalesced burst = %d, want 7 (5+2)", got)
+	}
+}
+
+func TestAdaptive_Failure(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Increment: 2, Interval: 20 * time.Millisecond})
+
+	a.Success()
+	a.Failure()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := a.Current(); got != 5 {
+		t.Errorf("Current() after Success() then Failure() = %d, want unchanged 5", got)
+	}
+}
+
+func TestAdaptive_ClampsToMaxCapacity(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Increment: 100, Interval: 10 * time.Millisecond})
+
+	a.Success()
+	time.Sleep(30 * time.Millisecond)
+
+	if got := a.Current(); got != 10 {
+		t.Errorf("Current() = %d, want clamped to maxCapacity 10", got)
+	}
+}
+
+func TestAdaptive_DoesNotExceedMaxCapacityWithOutstandingTokens(t *testing.T) {
+	ctx := context.Background()
+	tok, err := NewToken(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	a := NewAdaptive(tok, AdaptiveOpts{Increment: 1, Interval: 10 * time.Millisecond})
+	// NewAdaptive starts at maxCapacity/2 = 5, all of it available.
+
+	for i := 0; i < 5; i++ {
+		if err := tok.Take(ctx); err != nil {
+			t.Fatalf("Take() #%d: %v", i, err)
+		}
+	}
+	// All 5 available tokens are now checked out; none are available.
+
+	a.Success()
+	time.Sleep(30 * time.Millisecond)
+
+	if got := a.Current(); got != 6 {
+		t.Errorf("Current() after one Success() = %d, want 6 (5+1)", got)
+	}
+	if outstanding, avail := 5, tok.Available(); avail+outstanding > tok.MaxCapacity() {
+		t.Errorf("available (%d) + outstanding (%d) = %d exceeds maxCapacity (%d)", avail, outstanding, avail+outstanding, tok.MaxCapacity())
+	}
+}
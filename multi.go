@@ -0,0 +1,325 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MultiToken manages a set of independent Token buckets keyed by an
+// arbitrary string, such as a host name or tenant ID. Buckets are created
+// lazily on first use and evicted by a background janitor once they have
+// seen no traffic for the configured TTL.
+type MultiToken struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	defaultMaxCap int
+	defaultLength int
+	defaultRefill Limit
+	ttl           time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*multiEntry
+
+	// done is closed once the janitor goroutine has exited.
+	done chan struct{}
+}
+
+// multiEntry is a single bucket managed by a MultiToken, plus the state
+// needed to cancel it and report activity.
+type multiEntry struct {
+	token  *Token
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	lastAccess time.Time
+	// inFlight counts calls currently blocked in the underlying Token. A
+	// key with inFlight > 0 is never evicted, however long it has been
+	// since lastAccess was last updated.
+	inFlight int
+	takes    uint64
+	releases uint64
+	setCaps  uint64
+}
+
+// KeyStats reports usage counters for a single key managed by a MultiToken.
+type KeyStats struct {
+	Takes       uint64
+	Releases    uint64
+	SetCapacity uint64
+	LastAccess  time.Time
+}
+
+// NewMultiToken creates a MultiToken whose buckets default to the given
+// maximum capacity, initial length and refill rate. ctx governs the
+// lifecycle of every bucket and of the janitor goroutine.
+//
+// Parameters:
+//   - ctx: Context for managing the lifecycle of buckets and the janitor
+//   - maxCap: Default maximum capacity for lazily created buckets (must be > 0)
+//   - length: Default initial length for lazily created buckets (must be >= 0 and <= maxCap)
+//   - refill: Default refill rate for lazily created buckets; 0 disables time-based refill
+//   - ttl: How long a bucket may sit idle before the janitor evicts it (must be > 0)
+//
+// Returns an error if maxCap <= 0, length < 0, length > maxCap, or ttl <= 0.
+func NewMultiToken(ctx context.Context, maxCap, length int, refill Limit, ttl time.Duration) (*MultiToken, error) {
+	if maxCap <= 0 || length < 0 || maxCap < length {
+		return nil, fmt.Errorf("incorrect max capacity (%d) and/or length (%d)", maxCap, length)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("incorrect ttl (%v)", ttl)
+	}
+
+	ownCtx, cancel := context.WithCancel(ctx)
+
+	m := &MultiToken{
+		ctx:           ownCtx,
+		cancel:        cancel,
+		defaultMaxCap: maxCap,
+		defaultLength: length,
+		defaultRefill: refill,
+		ttl:           ttl,
+		buckets:       make(map[string]*multiEntry),
+		done:          make(chan struct{}),
+	}
+
+	go m.janitor()
+
+	return m, nil
+}
+
+// Close stops the janitor goroutine and, since every bucket's context is a
+// child of the MultiToken's own context, shuts down every currently
+// registered bucket along with it. It waits for the janitor to exit before
+// returning, and is safe to call even if the context passed to
+// NewMultiToken is never cancelled.
+func (m *MultiToken) Close() error {
+	m.cancel()
+	<-m.done
+	return nil
+}
+
+// Take acquires a token from the bucket for key, creating it with the
+// default settings if it does not already exist.
+func (m *MultiToken) Take(ctx context.Context, key string) error {
+	e, err := m.getOrCreate(key, m.defaultMaxCap, m.defaultLength, m.defaultRefill)
+	if err != nil {
+		return err
+	}
+	// Mark the key as in-flight before blocking in Take, not just after it
+	// returns, so the janitor doesn't mistake a long in-flight call (e.g.
+	// waiting on a refill-based bucket) for an idle key and evict it
+	// mid-call.
+	e.begin()
+	defer e.end()
+	if err := e.token.Take(ctx); err != nil {
+		return err
+	}
+	e.recordTake()
+	return nil
+}
+
+// Release returns a token to the bucket for key, creating it with the
+// default settings if it does not already exist.
+func (m *MultiToken) Release(ctx context.Context, key string) error {
+	e, err := m.getOrCreate(key, m.defaultMaxCap, m.defaultLength, m.defaultRefill)
+	if err != nil {
+		return err
+	}
+	e.begin()
+	defer e.end()
+	if err := e.token.Release(ctx); err != nil {
+		return err
+	}
+	e.recordRelease()
+	return nil
+}
+
+// SetCapacity adjusts the capacity of the bucket for key, creating it with
+// the default settings if it does not already exist.
+func (m *MultiToken) SetCapacity(ctx context.Context, key string, c int) error {
+	e, err := m.getOrCreate(key, m.defaultMaxCap, m.defaultLength, m.defaultRefill)
+	if err != nil {
+		return err
+	}
+	e.begin()
+	defer e.end()
+	if err := e.token.SetCapacity(ctx, c); err != nil {
+		return err
+	}
+	e.recordSetCapacity()
+	return nil
+}
+
+// Add pre-registers key with non-default settings, using the MultiToken's
+// default refill rate. It returns an error if key is already registered.
+func (m *MultiToken) Add(key string, maxCap, length int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.buckets[key]; ok {
+		return fmt.Errorf("token: key %q already registered", key)
+	}
+
+	e, err := m.newEntryLocked(maxCap, length, m.defaultRefill)
+	if err != nil {
+		return err
+	}
+	m.buckets[key] = e
+	return nil
+}
+
+// Remove forcibly shuts down and removes the bucket for key, if any. It is
+// a no-op if key is not registered.
+func (m *MultiToken) Remove(key string) {
+	m.mu.Lock()
+	e, ok := m.buckets[key]
+	if ok {
+		delete(m.buckets, key)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		e.cancel()
+	}
+}
+
+// Stats returns a snapshot of usage counters for every currently
+// registered key.
+func (m *MultiToken) Stats() map[string]KeyStats {
+	m.mu.Lock()
+	entries := make(map[string]*multiEntry, len(m.buckets))
+	for k, e := range m.buckets {
+		entries[k] = e
+	}
+	m.mu.Unlock()
+
+	stats := make(map[string]KeyStats, len(entries))
+	for k, e := range entries {
+		e.mu.Lock()
+		stats[k] = KeyStats{
+			Takes:       e.takes,
+			Releases:    e.releases,
+			SetCapacity: e.setCaps,
+			LastAccess:  e.lastAccess,
+		}
+		e.mu.Unlock()
+	}
+	return stats
+}
+
+// getOrCreate returns the existing bucket for key, or lazily creates one
+// with the given settings if this is the first time key is seen.
+func (m *MultiToken) getOrCreate(key string, maxCap, length int, refill Limit) (*multiEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.buckets[key]; ok {
+		return e, nil
+	}
+
+	e, err := m.newEntryLocked(maxCap, length, refill)
+	if err != nil {
+		return nil, err
+	}
+	m.buckets[key] = e
+	return e, nil
+}
+
+// newEntryLocked builds a bucket scoped to a child of the MultiToken's
+// context, so it can be shut down independently via Remove or the janitor.
+// Callers must hold m.mu.
+func (m *MultiToken) newEntryLocked(maxCap, length int, refill Limit) (*multiEntry, error) {
+	bucketCtx, cancel := context.WithCancel(m.ctx)
+
+	tok, err := NewTokenWithRate(bucketCtx, maxCap, length, refill)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &multiEntry{token: tok, cancel: cancel, lastAccess: time.Now()}, nil
+}
+
+// janitor periodically evicts buckets that have been idle for longer than
+// the configured TTL, until the MultiToken's context is cancelled.
+func (m *MultiToken) janitor() {
+	ticker := time.NewTicker(m.ttl)
+	defer ticker.Stop()
+	defer close(m.done)
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.evictIdle()
+		}
+	}
+}
+
+// evictIdle removes and shuts down every bucket whose last activity is
+// older than the TTL, skipping any bucket with a call currently in flight
+// regardless of how old lastAccess looks.
+func (m *MultiToken) evictIdle() {
+	cutoff := time.Now().Add(-m.ttl)
+
+	m.mu.Lock()
+	var stale []*multiEntry
+	for key, e := range m.buckets {
+		e.mu.Lock()
+		idle := e.inFlight == 0 && e.lastAccess.Before(cutoff)
+		e.mu.Unlock()
+
+		if idle {
+			stale = append(stale, e)
+			delete(m.buckets, key)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range stale {
+		e.cancel()
+	}
+}
+
+// begin marks the entry as having a call in flight, making it ineligible
+// for janitor eviction until a matching end. Callers must call end once
+// the call finishes, whether it succeeds or fails.
+func (e *multiEntry) begin() {
+	e.mu.Lock()
+	e.inFlight++
+	e.lastAccess = time.Now()
+	e.mu.Unlock()
+}
+
+// end reports that a call started by begin has finished.
+func (e *multiEntry) end() {
+	e.mu.Lock()
+	e.inFlight--
+	e.lastAccess = time.Now()
+	e.mu.Unlock()
+}
+
+func (e *multiEntry) recordTake() {
+	e.mu.Lock()
+	e.takes++
+	e.lastAccess = time.Now()
+	e.mu.Unlock()
+}
+
+func (e *multiEntry) recordRelease() {
+	e.mu.Lock()
+	e.releases++
+	e.lastAccess = time.Now()
+	e.mu.Unlock()
+}
+
+func (e *multiEntry) recordSetCapacity() {
+	e.mu.Lock()
+	e.setCaps++
+	e.lastAccess = time.Now()
+	e.mu.Unlock()
+}
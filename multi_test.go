@@ -0,0 +1,199 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewMultiToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxCap  int
+		length  int
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{name: "Valid parameters", maxCap: 10, length: 5, ttl: time.Minute, wantErr: false},
+		{name: "Zero max capacity", maxCap: 0, length: 0, ttl: time.Minute, wantErr: true},
+		{name: "Length greater than max capacity", maxCap: 5, length: 10, ttl: time.Minute, wantErr: true},
+		{name: "Zero TTL", maxCap: 10, length: 5, ttl: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := NewMultiToken(context.Background(), tt.maxCap, tt.length, 0, tt.ttl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewMultiToken() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && mt == nil {
+				t.Error("NewMultiToken() returned nil without error")
+			}
+		})
+	}
+}
+
+func TestMultiToken_LazyCreation(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 2, 1, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Take(ctx, "host-a"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	// The bucket for host-a only had 1 token, so a second take should block.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := mt.Take(timeoutCtx, "host-a"); err != context.DeadlineExceeded {
+		t.Errorf("Take() on exhausted key = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// A different key gets its own independent bucket.
+	if err := mt.Take(ctx, "host-b"); err != nil {
+		t.Errorf("Take() on new key failed: %v", err)
+	}
+}
+
+func TestMultiToken_Add(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 10, 5, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Add("tenant-1", 1, 1); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	if err := mt.Take(ctx, "tenant-1"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := mt.Take(timeoutCtx, "tenant-1"); err != context.DeadlineExceeded {
+		t.Errorf("Take() on a 1-capacity bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := mt.Add("tenant-1", 5, 5); err == nil {
+		t.Error("Add() on an already-registered key should fail")
+	}
+}
+
+func TestMultiToken_Remove(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 10, 5, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Take(ctx, "host-a"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	mt.Remove("host-a")
+
+	stats := mt.Stats()
+	if _, ok := stats["host-a"]; ok {
+		t.Error("Stats() should not report a removed key")
+	}
+}
+
+func TestMultiToken_Stats(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 10, 5, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Take(ctx, "host-a"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	if err := mt.Release(ctx, "host-a"); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+
+	stats := mt.Stats()
+	got, ok := stats["host-a"]
+	if !ok {
+		t.Fatal("Stats() missing key host-a")
+	}
+	if got.Takes != 1 || got.Releases != 1 {
+		t.Errorf("Stats()[host-a] = %+v, want Takes=1 Releases=1", got)
+	}
+}
+
+func TestMultiToken_TTLEviction(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 10, 5, 0, 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Take(ctx, "host-a"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	stats := mt.Stats()
+	if _, ok := stats["host-a"]; ok {
+		t.Error("janitor should have evicted the idle key")
+	}
+}
+
+func TestMultiToken_DoesNotEvictKeyWithInFlightTake(t *testing.T) {
+	ctx := context.Background()
+	// maxCap 1, no initial tokens, refills 1 token/sec: Take must block for
+	// ~1s waiting on the refill, well past the 50ms TTL below.
+	mt, err := NewMultiToken(ctx, 1, 0, 1, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	start := time.Now()
+	if err := mt.Take(ctx, "slow-key"); err != nil {
+		t.Fatalf("Take() failed: %v, want the blocking wait for refill to succeed", err)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("Take() returned after %v, want it to have waited for the refill", elapsed)
+	}
+}
+
+func TestMultiToken_Close(t *testing.T) {
+	ctx := context.Background()
+	mt, err := NewMultiToken(ctx, 10, 5, 0, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create MultiToken: %v", err)
+	}
+
+	if err := mt.Take(ctx, "host-a"); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- mt.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close() did not return: janitor goroutine never exited")
+	}
+
+	mt.mu.Lock()
+	e, ok := mt.buckets["host-a"]
+	mt.mu.Unlock()
+	if !ok {
+		t.Fatal("host-a bucket missing after Close()")
+	}
+	if !e.token.IsClosed() {
+		t.Error("bucket for host-a should be closed once its parent MultiToken is closed")
+	}
+}
@@ -1,25 +1,113 @@
 // Package token provides a dynamic token bucket implementation for rate limiting
 // and resource management. It allows for runtime adjustment of token capacity
-// while maintaining thread-safe operations through channels.
+// while maintaining thread-safe operations, and can additionally act as a
+// time-based rate limiter in the style of golang.org/x/time/rate.
 package token
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sync"
+	"time"
 )
 
-// Token represents a token bucket with dynamic capacity adjustment.
-// It uses channels for thread-safe token management and supports
-// context-based cancellation for all operations.
+// Limit defines the maximum rate at which tokens are replenished, expressed
+// in tokens per second. It mirrors golang.org/x/time/rate.Limit so that
+// callers familiar with that package feel at home.
+type Limit float64
+
+// Inf is an infinite rate limit; it causes the bucket to always appear full,
+// regardless of elapsed time. A zero Limit, by contrast, disables time-based
+// refill entirely so tokens only return via an explicit Release or
+// SetCapacity, matching the behaviour of a plain semaphore.
+const Inf = Limit(math.MaxFloat64)
+
+// ErrClosed is the cause reported by context.Cause on a Token's internal
+// context once Close or Shutdown has been called. Pending and future
+// Take, TakeN, Release, SetCapacity and Wait calls observe it through their
+// returned error. Reserve has no error result and does not check for
+// closure.
+var ErrClosed = errors.New("token: bucket is closed")
+
+// Token represents a token bucket with dynamic capacity adjustment and,
+// optionally, time-based refill. A mutex guards the bucket state and a
+// broadcast channel wakes blocked callers, so all operations support
+// context-based cancellation.
 type Token struct {
-	ctx context.Context
-	buf chan struct{}
-	// Maximum number of possible token
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu sync.Mutex
+	// notify is closed and replaced every time the bucket state changes,
+	// waking any goroutine blocked in a select on it.
+	notify chan struct{}
+
+	// Maximum number of possible tokens.
 	maxCapacity int
-	// Channel for capacity changes
-	cap chan int
-	// Actual number of tokens available
-	length int
+	// Actual number of tokens available. Kept as a float so fractional
+	// refill can accumulate between calls; it may briefly go negative
+	// after a Reserve to represent tokens owed by future refill.
+	length float64
+	// Tokens granted per second. Zero disables time-based refill.
+	refill     Limit
+	lastRefill time.Time
+
+	obs observer
+
+	// done is closed once the manager goroutine has exited.
+	done chan struct{}
+}
+
+// observer holds the optional instrumentation callbacks configured via
+// WithOnTake, WithOnRelease, WithOnWaitStart, WithOnWaitEnd and
+// WithOnCapacityChange. A nil field is simply never called.
+type observer struct {
+	onTake           func(length, maxCapacity float64)
+	onRelease        func(length, maxCapacity float64)
+	onWaitStart      func(op string)
+	onWaitEnd        func(op string, waited time.Duration)
+	onCapacityChange func(target, maxCapacity float64)
+}
+
+// Option configures optional behaviour of a Token, such as observability
+// hooks for metrics. Options are applied by NewToken and NewTokenWithRate.
+type Option func(*Token)
+
+// WithOnTake registers a callback fired every time a token is successfully
+// acquired (via Take, TakeN, TryTake or Reserve), with the bucket's length
+// and maxCapacity immediately after the acquisition.
+func WithOnTake(fn func(length, maxCapacity float64)) Option {
+	return func(t *Token) { t.obs.onTake = fn }
+}
+
+// WithOnRelease registers a callback fired every time a token is
+// successfully returned (via Release, TryRelease, or a Reservation's
+// Cancel), with the bucket's length and maxCapacity immediately after the
+// release.
+func WithOnRelease(fn func(length, maxCapacity float64)) Option {
+	return func(t *Token) { t.obs.onRelease = fn }
+}
+
+// WithOnWaitStart registers a callback fired when Take/TakeN or Release
+// starts blocking because the bucket cannot satisfy the call immediately.
+// op is "take" or "release".
+func WithOnWaitStart(fn func(op string)) Option {
+	return func(t *Token) { t.obs.onWaitStart = fn }
+}
+
+// WithOnWaitEnd registers a callback fired when a blocked Take/TakeN or
+// Release call returns, with the total time spent waiting. op is "take" or
+// "release". It is only fired if WithOnWaitStart also fired for that call.
+func WithOnWaitEnd(fn func(op string, waited time.Duration)) Option {
+	return func(t *Token) { t.obs.onWaitEnd = fn }
+}
+
+// WithOnCapacityChange registers a callback fired every time SetCapacity
+// succeeds, with the new target capacity and the bucket's maxCapacity.
+func WithOnCapacityChange(fn func(target, maxCapacity float64)) Option {
+	return func(t *Token) { t.obs.onCapacityChange = fn }
 }
 
 // NewToken creates a new Token bucket with the specified maximum capacity and initial length.
@@ -29,23 +117,48 @@ type Token struct {
 //   - ctx: Context for managing the token bucket lifecycle
 //   - maxCap: Maximum capacity of the token bucket (must be > 0)
 //   - len: Initial number of tokens (must be >= 0 and <= maxCap)
+//   - opts: Optional behaviour, such as instrumentation hooks (see WithOnTake et al.)
 //
 // Returns an error if maxCap <= 0, len < 0, or len > maxCap.
-func NewToken(ctx context.Context, maxCap, len int) (*Token, error) {
-	if maxCap <= 0 || len < 0 || maxCap < len {
-		return nil, fmt.Errorf("incorrect max capacity (%d) and/or length (%d)", maxCap, len)
+func NewToken(ctx context.Context, maxCap, len int, opts ...Option) (*Token, error) {
+	return NewTokenWithRate(ctx, maxCap, len, 0, opts...)
+}
+
+// NewTokenWithRate creates a new Token bucket that, in addition to behaving
+// as a dynamic-capacity semaphore, refills itself over time at refill tokens
+// per second, turning it into a rate limiter equivalent to x/time/rate.
+//
+// Parameters:
+//   - ctx: Context for managing the token bucket lifecycle
+//   - maxCap: Maximum capacity of the token bucket (must be > 0)
+//   - initial: Initial number of tokens (must be >= 0 and <= maxCap)
+//   - refill: Tokens granted per second; 0 disables time-based refill
+//   - opts: Optional behaviour, such as instrumentation hooks (see WithOnTake et al.)
+//
+// Returns an error if maxCap <= 0, initial < 0, initial > maxCap, or refill < 0.
+func NewTokenWithRate(ctx context.Context, maxCap, initial int, refill Limit, opts ...Option) (*Token, error) {
+	if maxCap <= 0 || initial < 0 || maxCap < initial {
+		return nil, fmt.Errorf("incorrect max capacity (%d) and/or length (%d)", maxCap, initial)
+	}
+	if refill < 0 {
+		return nil, fmt.Errorf("incorrect refill rate (%v)", refill)
 	}
 
+	ownCtx, cancel := context.WithCancelCause(ctx)
+
 	t := &Token{
-		ctx:         ctx,
-		buf:         make(chan struct{}, maxCap),
+		ctx:         ownCtx,
+		cancel:      cancel,
+		notify:      make(chan struct{}),
 		maxCapacity: maxCap,
-		cap:         make(chan int),
-		length:      len,
+		length:      float64(initial),
+		refill:      refill,
+		lastRefill:  time.Now(),
+		done:        make(chan struct{}),
 	}
 
-	for i := 0; i < t.length; i++ {
-		t.buf <- struct{}{}
+	for _, opt := range opts {
+		opt(t)
 	}
 
 	go t.manager()
@@ -53,6 +166,48 @@ func NewToken(ctx context.Context, maxCap, len int) (*Token, error) {
 	return t, nil
 }
 
+// Close shuts down the token bucket: every pending and future Take, TakeN,
+// Release, SetCapacity and Wait call observes ErrClosed via context.Cause. Close waits for the manager goroutine to exit before
+// returning, so it is safe to assume no more goroutines reference the
+// bucket once it returns.
+func (t *Token) Close() error {
+	t.cancel(ErrClosed)
+	<-t.done
+	return nil
+}
+
+// Shutdown waits for every outstanding token to be released, i.e. for the
+// bucket to return to full capacity, and then closes it via Close. If ctx
+// is cancelled first, Shutdown returns ctx's error without closing the
+// bucket.
+func (t *Token) Shutdown(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		t.refillLocked()
+		drained := t.length >= float64(t.maxCapacity)
+		notify := t.notify
+		t.mu.Unlock()
+
+		if drained {
+			return t.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-t.ctx.Done():
+			return context.Cause(t.ctx)
+		case <-notify:
+		}
+	}
+}
+
+// IsClosed reports whether the bucket has been closed, either via Close,
+// Shutdown, or cancellation of the context passed to NewToken/NewTokenWithRate.
+func (t *Token) IsClosed() bool {
+	return t.ctx.Err() != nil
+}
+
 // Take acquires a token from the bucket, blocking until one is available
 // or the context is cancelled.
 //
@@ -60,13 +215,88 @@ func NewToken(ctx context.Context, maxCap, len int) (*Token, error) {
 //   - nil if a token was successfully acquired
 //   - context error if the operation was cancelled
 func (t *Token) Take(ctx context.Context) error {
-	select {
-	case <-ctx.Done():
-		return context.Cause(ctx)
-	case <-t.ctx.Done():
+	return t.TakeN(ctx, 1)
+}
+
+// TakeN acquires n tokens from the bucket, blocking until all n are
+// available or the context is cancelled. When the bucket has a configured
+// refill rate, waiting accounts for tokens accruing over time; otherwise it
+// waits for an explicit Release or SetCapacity.
+//
+// Returns:
+//   - nil if n tokens were successfully acquired
+//   - an error immediately if n exceeds the bucket's maximum capacity, since
+//     it could never be satisfied
+//   - context error if the operation was cancelled
+func (t *Token) TakeN(ctx context.Context, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("n must be positive, got %d", n)
+	}
+	if n > t.maxCapacity {
+		return fmt.Errorf("n (%d) exceeds maximum capacity (%d)", n, t.maxCapacity)
+	}
+	if err := t.ctx.Err(); err != nil {
 		return context.Cause(t.ctx)
-	case <-t.buf:
-		return nil
+	}
+
+	start := time.Now()
+	waiting := false
+
+	for {
+		t.mu.Lock()
+		t.refillLocked()
+		if t.length >= float64(n) {
+			t.length -= float64(n)
+			length := t.length
+			t.wakeLocked()
+			t.mu.Unlock()
+
+			t.fireTake(length)
+			if waiting {
+				t.fireWaitEnd("take", time.Since(start))
+			}
+			return nil
+		}
+
+		deficit := float64(n) - t.length
+		notify := t.notify
+		var timer *time.Timer
+		if t.refill > 0 {
+			timer = time.NewTimer(time.Duration(deficit / float64(t.refill) * float64(time.Second)))
+		}
+		t.mu.Unlock()
+
+		if !waiting {
+			waiting = true
+			t.fireWaitStart("take")
+		}
+
+		if timer == nil {
+			select {
+			case <-ctx.Done():
+				t.fireWaitEnd("take", time.Since(start))
+				return context.Cause(ctx)
+			case <-t.ctx.Done():
+				t.fireWaitEnd("take", time.Since(start))
+				return context.Cause(t.ctx)
+			case <-notify:
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			t.fireWaitEnd("take", time.Since(start))
+			return context.Cause(ctx)
+		case <-t.ctx.Done():
+			timer.Stop()
+			t.fireWaitEnd("take", time.Since(start))
+			return context.Cause(t.ctx)
+		case <-notify:
+			timer.Stop()
+		case <-timer.C:
+		}
 	}
 }
 
@@ -77,20 +307,259 @@ func (t *Token) Take(ctx context.Context) error {
 //   - nil if the token was successfully released
 //   - context error if the operation was cancelled
 func (t *Token) Release(ctx context.Context) error {
+	if err := t.ctx.Err(); err != nil {
+		return context.Cause(t.ctx)
+	}
+
+	start := time.Now()
+	waiting := false
+
+	for {
+		t.mu.Lock()
+		t.refillLocked()
+		if t.length+1 <= float64(t.maxCapacity) {
+			t.length++
+			length := t.length
+			t.wakeLocked()
+			t.mu.Unlock()
+
+			t.fireRelease(length)
+			if waiting {
+				t.fireWaitEnd("release", time.Since(start))
+			}
+			return nil
+		}
+		notify := t.notify
+		t.mu.Unlock()
+
+		if !waiting {
+			waiting = true
+			t.fireWaitStart("release")
+		}
+
+		select {
+		case <-ctx.Done():
+			t.fireWaitEnd("release", time.Since(start))
+			return context.Cause(ctx)
+		case <-t.ctx.Done():
+			t.fireWaitEnd("release", time.Since(start))
+			return context.Cause(t.ctx)
+		case <-notify:
+		}
+	}
+}
+
+// TryTake attempts to acquire a token without blocking. It returns false
+// instead of waiting when the bucket is empty.
+//
+// Returns:
+//   - (true, nil) if a token was acquired
+//   - (false, nil) if the bucket has no tokens available
+//   - (false, err) if ctx or the token's own context is already cancelled
+func (t *Token) TryTake(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, context.Cause(ctx)
+	}
+	if err := t.ctx.Err(); err != nil {
+		return false, context.Cause(t.ctx)
+	}
+
+	t.mu.Lock()
+	t.refillLocked()
+
+	if t.length < 1 {
+		t.mu.Unlock()
+		return false, nil
+	}
+
+	t.length--
+	length := t.length
+	t.wakeLocked()
+	t.mu.Unlock()
+
+	t.fireTake(length)
+	return true, nil
+}
+
+// TryRelease attempts to return a token without blocking. It returns false
+// instead of waiting when the bucket is already at its maximum capacity.
+//
+// Returns:
+//   - (true, nil) if the token was released
+//   - (false, nil) if the bucket is already full
+//   - (false, err) if ctx or the token's own context is already cancelled
+func (t *Token) TryRelease(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, context.Cause(ctx)
+	}
+	if err := t.ctx.Err(); err != nil {
+		return false, context.Cause(t.ctx)
+	}
+
+	t.mu.Lock()
+	t.refillLocked()
+
+	if t.length+1 > float64(t.maxCapacity) {
+		t.mu.Unlock()
+		return false, nil
+	}
+
+	t.length++
+	length := t.length
+	t.wakeLocked()
+	t.mu.Unlock()
+
+	t.fireRelease(length)
+	return true, nil
+}
+
+// Available returns the current number of tokens available to Take,
+// rounded down to the nearest whole token.
+func (t *Token) Available() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refillLocked()
+
+	if t.length < 0 {
+		return 0
+	}
+	return int(t.length)
+}
+
+// Capacity returns the bucket's current capacity. In this implementation
+// that is the same count as Available, since SetCapacity adjusts the
+// available tokens directly rather than a separate ceiling: it starts at the
+// value set via SetCapacity (or the initial length) and drops with every
+// Take, rises with every Release and with refill if a rate is configured.
+func (t *Token) Capacity() int {
+	return t.Available()
+}
+
+// MaxCapacity returns the maximum number of tokens the bucket can ever hold.
+func (t *Token) MaxCapacity() int {
+	return t.maxCapacity
+}
+
+// Reservation holds the outcome of a call to Reserve: either the tokens were
+// committed immediately, or after Delay has elapsed.
+type Reservation struct {
+	ok    bool
+	delay time.Duration
+	t     *Token
+	n     float64
+}
+
+// OK reports whether the reservation could be satisfied at all. It is false
+// when n exceeds the bucket's maximum capacity, or when the bucket has no
+// refill rate and not enough tokens were available to cover n immediately.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the caller should wait before the reserved tokens
+// are actually available. It is zero when the tokens were available
+// immediately.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel returns the reservation's tokens to the bucket. It is a no-op if
+// the reservation was not OK.
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+
+	r.t.mu.Lock()
+	r.t.refillLocked()
+	r.t.length = math.Min(r.t.length+r.n, float64(r.t.maxCapacity))
+	length := r.t.length
+	r.t.wakeLocked()
+	r.t.mu.Unlock()
+
+	r.t.fireRelease(length)
+}
+
+// Reserve commits n tokens without blocking and reports how long the caller
+// must wait before it is safe to proceed, analogous to rate.Limiter.ReserveN.
+// Callers that give up before Delay elapses should call Cancel to return the
+// reserved tokens.
+func (t *Token) Reserve(n int) *Reservation {
+	if n <= 0 || n > t.maxCapacity {
+		return &Reservation{ok: false}
+	}
+
+	t.mu.Lock()
+	t.refillLocked()
+
+	if t.length >= float64(n) {
+		t.length -= float64(n)
+		length := t.length
+		t.wakeLocked()
+		t.mu.Unlock()
+
+		t.fireTake(length)
+		return &Reservation{ok: true, t: t, n: float64(n)}
+	}
+
+	if t.refill <= 0 {
+		// No refill configured: there is no way to predict when enough
+		// tokens will return via an explicit Release or SetCapacity.
+		t.mu.Unlock()
+		return &Reservation{ok: false}
+	}
+
+	deficit := float64(n) - t.length
+	t.length -= float64(n)
+	length := t.length
+	t.mu.Unlock()
+
+	t.fireTake(length)
+	return &Reservation{
+		ok:    true,
+		t:     t,
+		n:     float64(n),
+		delay: time.Duration(deficit / float64(t.refill) * float64(time.Second)),
+	}
+}
+
+// Wait blocks until n tokens are available, honouring the bucket's refill
+// rate, or until ctx is cancelled. If ctx is cancelled before the reserved
+// tokens become available, they are returned to the bucket.
+//
+// Returns:
+//   - nil once n tokens have been acquired
+//   - an error if n cannot be satisfied or the context is cancelled
+func (t *Token) Wait(ctx context.Context, n int) error {
+	if err := t.ctx.Err(); err != nil {
+		return context.Cause(t.ctx)
+	}
+
+	r := t.Reserve(n)
+	if !r.OK() {
+		return fmt.Errorf("token: cannot reserve %d tokens (max capacity %d)", n, t.maxCapacity)
+	}
+	if r.Delay() <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(r.Delay())
+	defer timer.Stop()
+
 	select {
 	case <-ctx.Done():
+		r.Cancel()
 		return context.Cause(ctx)
 	case <-t.ctx.Done():
+		r.Cancel()
 		return context.Cause(t.ctx)
-	case t.buf <- struct{}{}:
+	case <-timer.C:
 		return nil
-
 	}
 }
 
 // SetCapacity dynamically adjusts the number of available tokens in the bucket.
 // The new capacity must be between 0 and the maximum capacity set during initialization.
-// The adjustment is handled asynchronously by the internal manager goroutine.
 //
 // Parameters:
 //   - ctx: Context for the operation
@@ -101,45 +570,113 @@ func (t *Token) SetCapacity(ctx context.Context, c int) error {
 	if c > t.maxCapacity || c < 0 {
 		return fmt.Errorf("capacity (%d) should be between 0 and maximum (%d)", c, t.maxCapacity)
 	}
-
-	select {
-	case <-ctx.Done():
+	if err := ctx.Err(); err != nil {
 		return context.Cause(ctx)
-	case <-t.ctx.Done():
+	}
+	if err := t.ctx.Err(); err != nil {
 		return context.Cause(t.ctx)
-	case t.cap <- c:
 	}
+
+	t.mu.Lock()
+	t.length = float64(c)
+	t.lastRefill = time.Now()
+	t.wakeLocked()
+	t.mu.Unlock()
+
+	t.fireCapacityChange(float64(c))
 	return nil
 }
 
-// manager is an internal goroutine that handles dynamic capacity adjustments.
-// It continuously monitors for capacity change requests and adjusts the number
-// of tokens in the bucket accordingly by either adding or removing tokens.
-func (t *Token) manager() {
-	var in chan<- struct{}
-	var out <-chan struct{}
-	target := t.length
+// adjustCapacity atomically adds delta to the bucket's available tokens,
+// clamping the result to [0, maxCapacity], and fires onCapacityChange as
+// SetCapacity would. Unlike calling Available followed by SetCapacity, the
+// read and the write happen under a single lock acquisition, so a
+// concurrent Take or Release landing between them is not silently
+// clobbered. It is a no-op once the bucket is closed.
+func (t *Token) adjustCapacity(delta int) {
+	if err := t.ctx.Err(); err != nil {
+		return
+	}
 
-	for {
-		select {
-		case <-t.ctx.Done():
-			return
-		case in <- struct{}{}:
-			t.length++
-		case <-out:
-			t.length--
-		case target = <-t.cap:
-		}
+	t.mu.Lock()
+	t.refillLocked()
+	t.length = math.Min(math.Max(t.length+float64(delta), 0), float64(t.maxCapacity))
+	t.lastRefill = time.Now()
+	length := t.length
+	t.wakeLocked()
+	t.mu.Unlock()
 
-		if target > t.length {
-			in = t.buf
-			out = nil
-		} else if target < t.length {
-			in = nil
-			out = t.buf
-		} else {
-			in = nil
-			out = nil
-		}
+	t.fireCapacityChange(length)
+}
+
+// fireTake invokes the onTake hook, if configured.
+func (t *Token) fireTake(length float64) {
+	if t.obs.onTake != nil {
+		t.obs.onTake(length, float64(t.maxCapacity))
+	}
+}
+
+// fireRelease invokes the onRelease hook, if configured.
+func (t *Token) fireRelease(length float64) {
+	if t.obs.onRelease != nil {
+		t.obs.onRelease(length, float64(t.maxCapacity))
+	}
+}
+
+// fireWaitStart invokes the onWaitStart hook, if configured.
+func (t *Token) fireWaitStart(op string) {
+	if t.obs.onWaitStart != nil {
+		t.obs.onWaitStart(op)
+	}
+}
+
+// fireWaitEnd invokes the onWaitEnd hook, if configured.
+func (t *Token) fireWaitEnd(op string, waited time.Duration) {
+	if t.obs.onWaitEnd != nil {
+		t.obs.onWaitEnd(op, waited)
+	}
+}
+
+// fireCapacityChange invokes the onCapacityChange hook, if configured.
+func (t *Token) fireCapacityChange(target float64) {
+	if t.obs.onCapacityChange != nil {
+		t.obs.onCapacityChange(target, float64(t.maxCapacity))
+	}
+}
+
+// refillLocked advances the available token count based on elapsed time and
+// the configured refill rate, capping it at maxCapacity. Callers must hold t.mu.
+func (t *Token) refillLocked() {
+	if t.refill <= 0 {
+		return
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(t.lastRefill).Seconds()
+	if elapsed <= 0 {
+		return
 	}
+
+	t.length = math.Min(t.length+elapsed*float64(t.refill), float64(t.maxCapacity))
+	t.lastRefill = now
+}
+
+// wakeLocked notifies every goroutine blocked on the current notify channel
+// that the bucket state has changed. Callers must hold t.mu.
+func (t *Token) wakeLocked() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+// manager is an internal goroutine that waits for the token's context to be
+// cancelled, then wakes every blocked caller so they can observe the
+// cancellation, and signals done once it has finished.
+func (t *Token) manager() {
+	<-t.ctx.Done()
+
+	t.mu.Lock()
+	t.wakeLocked()
+	t.mu.Unlock()
+
+	close(t.done)
 }
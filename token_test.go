@@ -4,6 +4,7 @@ package token
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -321,8 +322,9 @@ func TestToken_DynamicCapacityAdjustment(t *testing.T) {
 		// Allow manager goroutine to process
 		time.Sleep(50 * time.Millisecond)
 
-		// Should be able to take 2 more tokens
-		for i := 0; i < 2; i++ {
+		// SetCapacity sets the absolute token count, so all 4 should now be
+		// available to take.
+		for i := 0; i < 4; i++ {
 			timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
 			err = token.Take(timeoutCtx)
 			cancel()
@@ -331,7 +333,7 @@ func TestToken_DynamicCapacityAdjustment(t *testing.T) {
 			}
 		}
 
-		// Third take should block
+		// Fifth take should block
 		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
 		defer cancel()
 		err = token.Take(timeoutCtx)
@@ -510,6 +512,579 @@ func TestToken_ContextCancellation(t *testing.T) {
 	})
 }
 
+func TestNewTokenWithRate(t *testing.T) {
+	t.Run("Negative refill rejected", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := NewTokenWithRate(ctx, 10, 5, -1)
+		if err == nil {
+			t.Error("NewTokenWithRate() with negative refill should fail")
+		}
+	})
+
+	t.Run("Zero refill behaves like NewToken", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewTokenWithRate(ctx, 10, 0, 0)
+		if err != nil {
+			t.Fatalf("NewTokenWithRate() failed: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if err := token.Take(timeoutCtx); err != context.DeadlineExceeded {
+			t.Errorf("Take() on empty zero-refill token = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestToken_TakeN(t *testing.T) {
+	t.Run("TakeN available tokens", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.TakeN(ctx, 3); err != nil {
+			t.Errorf("TakeN() failed: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		if err := token.TakeN(timeoutCtx, 3); err != context.DeadlineExceeded {
+			t.Errorf("TakeN() beyond availability = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("TakeN rejects non-positive n", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.TakeN(ctx, 0); err == nil {
+			t.Error("TakeN(0) should fail")
+		}
+	})
+
+	t.Run("TakeN rejects n beyond maxCapacity immediately", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := token.TakeN(timeoutCtx, 11); err == nil {
+			t.Error("TakeN(11) on a bucket with maxCapacity 10 should fail")
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("TakeN(11) took %v, want an immediate error instead of waiting out the context", elapsed)
+		}
+	})
+
+	t.Run("TakeN waits for refill", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewTokenWithRate(ctx, 10, 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		start := time.Now()
+		if err := token.TakeN(ctx, 5); err != nil {
+			t.Fatalf("TakeN() failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("TakeN() returned after %v, expected to wait for refill", elapsed)
+		}
+	})
+}
+
+func TestToken_Reserve(t *testing.T) {
+	t.Run("Reserve within available tokens", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(3)
+		if !r.OK() {
+			t.Fatal("Reserve() should succeed")
+		}
+		if r.Delay() != 0 {
+			t.Errorf("Reserve() delay = %v, want 0", r.Delay())
+		}
+	})
+
+	t.Run("Reserve above maximum capacity fails", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(11)
+		if r.OK() {
+			t.Error("Reserve() above maxCapacity should not be OK")
+		}
+	})
+
+	t.Run("Reserve without refill fails when insufficient", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 2)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(5)
+		if r.OK() {
+			t.Error("Reserve() without a refill rate should fail when tokens are insufficient")
+		}
+	})
+
+	t.Run("Reserve with refill reports a delay", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewTokenWithRate(ctx, 10, 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(5)
+		if !r.OK() {
+			t.Fatal("Reserve() should be OK when a refill rate is configured")
+		}
+		if r.Delay() <= 0 {
+			t.Errorf("Reserve() delay = %v, want > 0", r.Delay())
+		}
+	})
+
+	t.Run("Cancel returns reserved tokens", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(5)
+		if !r.OK() {
+			t.Fatal("Reserve() should succeed")
+		}
+		r.Cancel()
+
+		if err := token.Take(ctx); err != nil {
+			t.Errorf("Take() after Cancel() failed: %v", err)
+		}
+	})
+}
+
+func TestToken_Wait(t *testing.T) {
+	t.Run("Wait returns immediately when tokens available", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.Wait(ctx, 3); err != nil {
+			t.Errorf("Wait() failed: %v", err)
+		}
+	})
+
+	t.Run("Wait blocks until refill catches up", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewTokenWithRate(ctx, 10, 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		start := time.Now()
+		if err := token.Wait(ctx, 5); err != nil {
+			t.Fatalf("Wait() failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("Wait() returned after %v, expected to wait for refill", elapsed)
+		}
+	})
+
+	t.Run("Wait cancelled before tokens available returns them", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewTokenWithRate(ctx, 10, 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		err = token.Wait(timeoutCtx, 5)
+		if err != context.DeadlineExceeded {
+			t.Errorf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		// The cancelled reservation's 5 tokens should have been returned,
+		// so the tokens accrued during the wait (~2 at this refill rate)
+		// should be immediately takeable.
+		takeCtx, takeCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer takeCancel()
+		if err := token.Take(takeCtx); err != nil {
+			t.Errorf("Take() after cancelled Wait() should succeed since reservation was returned: %v", err)
+		}
+	})
+}
+
+func TestToken_TryTake(t *testing.T) {
+	t.Run("TryTake succeeds when tokens available", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 1)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		ok, err := token.TryTake(ctx)
+		if err != nil || !ok {
+			t.Errorf("TryTake() = (%v, %v), want (true, nil)", ok, err)
+		}
+	})
+
+	t.Run("TryTake returns false instead of blocking", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		ok, err := token.TryTake(ctx)
+		if err != nil || ok {
+			t.Errorf("TryTake() on empty bucket = (%v, %v), want (false, nil)", ok, err)
+		}
+	})
+
+	t.Run("TryTake fails on cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		token, err := NewToken(context.Background(), 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if _, err := token.TryTake(ctx); err != context.Canceled {
+			t.Errorf("TryTake() with cancelled context = %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func TestToken_TryRelease(t *testing.T) {
+	t.Run("TryRelease succeeds when room available", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		ok, err := token.TryRelease(ctx)
+		if err != nil || !ok {
+			t.Errorf("TryRelease() = (%v, %v), want (true, nil)", ok, err)
+		}
+	})
+
+	t.Run("TryRelease returns false instead of blocking", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 2, 2)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		ok, err := token.TryRelease(ctx)
+		if err != nil || ok {
+			t.Errorf("TryRelease() on full bucket = (%v, %v), want (false, nil)", ok, err)
+		}
+	})
+}
+
+func TestToken_Accessors(t *testing.T) {
+	ctx := context.Background()
+	token, err := NewToken(ctx, 10, 5)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	if got := token.MaxCapacity(); got != 10 {
+		t.Errorf("MaxCapacity() = %d, want 10", got)
+	}
+	if got := token.Available(); got != 5 {
+		t.Errorf("Available() = %d, want 5", got)
+	}
+	if got := token.Capacity(); got != 5 {
+		t.Errorf("Capacity() = %d, want 5", got)
+	}
+
+	if err := token.Take(ctx); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+	if got := token.Available(); got != 4 {
+		t.Errorf("Available() after Take() = %d, want 4", got)
+	}
+
+	if err := token.SetCapacity(ctx, 8); err != nil {
+		t.Fatalf("SetCapacity() failed: %v", err)
+	}
+	if got := token.Available(); got != 8 {
+		t.Errorf("Available() after SetCapacity(8) = %d, want 8", got)
+	}
+}
+
+func TestToken_Observer(t *testing.T) {
+	t.Run("WithOnTake and WithOnRelease fire on success", func(t *testing.T) {
+		ctx := context.Background()
+		var takes, releases int
+
+		token, err := NewToken(ctx, 10, 5,
+			WithOnTake(func(length, maxCapacity float64) { takes++ }),
+			WithOnRelease(func(length, maxCapacity float64) { releases++ }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.Take(ctx); err != nil {
+			t.Fatalf("Take() failed: %v", err)
+		}
+		if err := token.Release(ctx); err != nil {
+			t.Fatalf("Release() failed: %v", err)
+		}
+
+		if takes != 1 {
+			t.Errorf("onTake fired %d times, want 1", takes)
+		}
+		if releases != 1 {
+			t.Errorf("onRelease fired %d times, want 1", releases)
+		}
+	})
+
+	t.Run("WithOnWaitStart and WithOnWaitEnd fire only when blocking", func(t *testing.T) {
+		ctx := context.Background()
+		var waitStarts int
+		var waitEnds []string
+
+		token, err := NewToken(ctx, 10, 0,
+			WithOnWaitStart(func(op string) { waitStarts++ }),
+			WithOnWaitEnd(func(op string, waited time.Duration) { waitEnds = append(waitEnds, op) }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if err := token.Take(timeoutCtx); err != context.DeadlineExceeded {
+			t.Fatalf("Take() = %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		if waitStarts != 1 {
+			t.Errorf("onWaitStart fired %d times, want 1", waitStarts)
+		}
+		if len(waitEnds) != 1 || waitEnds[0] != "take" {
+			t.Errorf("onWaitEnd = %v, want [take]", waitEnds)
+		}
+
+		// A successful, non-blocking Take should not fire wait hooks.
+		if err := token.Release(ctx); err != nil {
+			t.Fatalf("Release() failed: %v", err)
+		}
+		waitStarts, waitEnds = 0, nil
+		if err := token.Take(ctx); err != nil {
+			t.Fatalf("Take() failed: %v", err)
+		}
+		if waitStarts != 0 || len(waitEnds) != 0 {
+			t.Errorf("wait hooks fired for a non-blocking Take: starts=%d ends=%v", waitStarts, waitEnds)
+		}
+	})
+
+	t.Run("WithOnTake and WithOnRelease fire for Reserve and Cancel", func(t *testing.T) {
+		ctx := context.Background()
+		var takes, releases int
+
+		token, err := NewToken(ctx, 10, 5,
+			WithOnTake(func(length, maxCapacity float64) { takes++ }),
+			WithOnRelease(func(length, maxCapacity float64) { releases++ }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		r := token.Reserve(3)
+		if !r.OK() {
+			t.Fatalf("Reserve(3) not OK")
+		}
+		if takes != 1 {
+			t.Errorf("onTake fired %d times after Reserve, want 1", takes)
+		}
+
+		r.Cancel()
+		if releases != 1 {
+			t.Errorf("onRelease fired %d times after Cancel, want 1", releases)
+		}
+
+		if err := token.Wait(ctx, 2); err != nil {
+			t.Fatalf("Wait() failed: %v", err)
+		}
+		if takes != 2 {
+			t.Errorf("onTake fired %d times after Wait, want 2", takes)
+		}
+	})
+
+	t.Run("WithOnCapacityChange fires on SetCapacity", func(t *testing.T) {
+		ctx := context.Background()
+		var got float64 = -1
+
+		token, err := NewToken(ctx, 10, 5,
+			WithOnCapacityChange(func(target, maxCapacity float64) { got = target }),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.SetCapacity(ctx, 7); err != nil {
+			t.Fatalf("SetCapacity() failed: %v", err)
+		}
+		if got != 7 {
+			t.Errorf("onCapacityChange target = %v, want 7", got)
+		}
+	})
+}
+
+func TestToken_Close(t *testing.T) {
+	t.Run("Close causes pending and future calls to return ErrClosed", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- token.Take(ctx) }()
+
+		// Give the goroutine a chance to start blocking in Take.
+		time.Sleep(20 * time.Millisecond)
+
+		if err := token.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+
+		select {
+		case err := <-errCh:
+			if !errors.Is(err, ErrClosed) {
+				t.Errorf("pending Take() error = %v, want ErrClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Take() did not return after Close()")
+		}
+
+		if err := token.Take(ctx); !errors.Is(err, ErrClosed) {
+			t.Errorf("Take() after Close() = %v, want ErrClosed", err)
+		}
+		if err := token.SetCapacity(ctx, 5); !errors.Is(err, ErrClosed) {
+			t.Errorf("SetCapacity() after Close() = %v, want ErrClosed", err)
+		}
+	})
+
+	t.Run("IsClosed reflects Close", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if token.IsClosed() {
+			t.Error("IsClosed() = true before Close()")
+		}
+
+		if err := token.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+
+		if !token.IsClosed() {
+			t.Error("IsClosed() = false after Close()")
+		}
+	})
+
+	t.Run("Close is safe to call twice", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 5)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.Close(); err != nil {
+			t.Fatalf("first Close() failed: %v", err)
+		}
+		if err := token.Close(); err != nil {
+			t.Errorf("second Close() failed: %v", err)
+		}
+	})
+}
+
+func TestToken_Shutdown(t *testing.T) {
+	t.Run("Shutdown closes once all tokens are released", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 10)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		if err := token.Take(ctx); err != nil {
+			t.Fatalf("Take() failed: %v", err)
+		}
+
+		done := make(chan error, 1)
+		go func() { done <- token.Shutdown(ctx) }()
+
+		time.Sleep(20 * time.Millisecond)
+		if token.IsClosed() {
+			t.Error("Shutdown() should not close before the outstanding token is released")
+		}
+
+		if err := token.Release(ctx); err != nil {
+			t.Fatalf("Release() failed: %v", err)
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Shutdown() failed: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Shutdown() did not return after the token was released")
+		}
+
+		if !token.IsClosed() {
+			t.Error("IsClosed() = false after Shutdown()")
+		}
+	})
+
+	t.Run("Shutdown returns ctx error without closing if cancelled first", func(t *testing.T) {
+		ctx := context.Background()
+		token, err := NewToken(ctx, 10, 0)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		if err := token.Shutdown(timeoutCtx); err != context.DeadlineExceeded {
+			t.Errorf("Shutdown() = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if token.IsClosed() {
+			t.Error("IsClosed() = true after a cancelled Shutdown()")
+		}
+	})
+}
+
 func BenchmarkToken_Take(b *testing.B) {
 	ctx := context.Background()
 	token, err := NewToken(ctx, b.N, b.N)
@@ -0,0 +1,110 @@
+// Package tokenprom exposes a token.Token's state and activity as
+// Prometheus metrics. It is an optional integration: importing it pulls in
+// github.com/prometheus/client_golang, which the root token package does
+// not otherwise depend on.
+package tokenprom
+
+import (
+	"time"
+
+	"github.com/mihkulemin/token"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements prometheus.Collector for a single token.Token. The
+// two-step New/Bind construction exists because the instrumentation
+// options must be passed into token.NewToken/NewTokenWithRate before the
+// Token itself exists.
+//
+// Typical usage:
+//
+//	c := tokenprom.New("my-bucket")
+//	tok, err := token.NewTokenWithRate(ctx, 100, 100, 50, c.Options()...)
+//	c.Bind(tok)
+//	registry.MustRegister(c)
+type Collector struct {
+	labels prometheus.Labels
+
+	available   prometheus.GaugeFunc
+	capacity    prometheus.GaugeFunc
+	takeWait    prometheus.Histogram
+	releaseWait prometheus.Histogram
+}
+
+// New creates a Collector for a bucket identified by name. name is attached
+// to every metric as a "bucket" label.
+func New(name string) *Collector {
+	labels := prometheus.Labels{"bucket": name}
+
+	return &Collector{
+		labels: labels,
+		takeWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "tokens_take_wait_seconds",
+			Help:        "Time spent blocked in Take/TakeN waiting for tokens.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}),
+		releaseWait: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:        "tokens_release_wait_seconds",
+			Help:        "Time spent blocked in Release waiting for bucket space.",
+			ConstLabels: labels,
+			Buckets:     prometheus.DefBuckets,
+		}),
+	}
+}
+
+// Bind attaches the Collector to tok, so its gauges report the bucket's
+// live state. It must be called once, after tok is constructed with
+// Options() and before the Collector is registered.
+func (c *Collector) Bind(tok *token.Token) {
+	c.available = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "tokens_available",
+		Help:        "Number of tokens currently available to Take.",
+		ConstLabels: c.labels,
+	}, func() float64 { return float64(tok.Available()) })
+
+	c.capacity = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "tokens_capacity",
+		Help:        "Current capacity of the bucket, as reported by Token.Capacity.",
+		ConstLabels: c.labels,
+	}, func() float64 { return float64(tok.Capacity()) })
+}
+
+// Options returns the token.Option values that feed this Collector's wait
+// histograms. Pass them to token.NewToken or token.NewTokenWithRate.
+func (c *Collector) Options() []token.Option {
+	return []token.Option{
+		token.WithOnWaitEnd(func(op string, waited time.Duration) {
+			switch op {
+			case "take":
+				c.takeWait.Observe(waited.Seconds())
+			case "release":
+				c.releaseWait.Observe(waited.Seconds())
+			}
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	if c.available != nil {
+		c.available.Describe(ch)
+	}
+	if c.capacity != nil {
+		c.capacity.Describe(ch)
+	}
+	c.takeWait.Describe(ch)
+	c.releaseWait.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	if c.available != nil {
+		c.available.Collect(ch)
+	}
+	if c.capacity != nil {
+		c.capacity.Collect(ch)
+	}
+	c.takeWait.Collect(ch)
+	c.releaseWait.Collect(ch)
+}
@@ -0,0 +1,73 @@
+package tokenprom
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mihkulemin/token"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector_MultipleBucketsRegister(t *testing.T) {
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+
+	for _, name := range []string{"bucket-a", "bucket-b"} {
+		c := New(name)
+		tok, err := token.NewToken(ctx, 10, 5, c.Options()...)
+		if err != nil {
+			t.Fatalf("Failed to create token for %s: %v", name, err)
+		}
+		c.Bind(tok)
+
+		if err := registry.Register(c); err != nil {
+			t.Fatalf("Register() for %s failed: %v", name, err)
+		}
+	}
+
+	if _, err := registry.Gather(); err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+}
+
+func TestCollector_ReportsLiveState(t *testing.T) {
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+
+	c := New("my-bucket")
+	tok, err := token.NewToken(ctx, 10, 5, c.Options()...)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	c.Bind(tok)
+
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("Register() failed: %v", err)
+	}
+
+	if err := tok.Take(ctx); err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+
+	var gotAvailable bool
+	for _, f := range families {
+		if f.GetName() != "tokens_available" {
+			continue
+		}
+		gotAvailable = true
+		if got := f.GetMetric()[0].GetGauge().GetValue(); got != 4 {
+			t.Errorf("tokens_available = %v, want 4", got)
+		}
+		if got := f.GetMetric()[0].GetLabel()[0].GetValue(); got != "my-bucket" {
+			t.Errorf("bucket label = %q, want %q", got, "my-bucket")
+		}
+	}
+	if !gotAvailable {
+		t.Error("tokens_available metric not found")
+	}
+}